@@ -0,0 +1,146 @@
+package ebpf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf/link"
+)
+
+// AttachOptions supplies the external context AttachAll needs to resolve
+// attachment points that a program's section name alone doesn't carry.
+type AttachOptions struct {
+	// Interface is the network device index XDP programs attach to.
+	Interface int
+
+	// Cgroup is the cgroupv2 directory cgroup/* programs attach to.
+	Cgroup *os.File
+
+	// Executable is the path to the binary or shared library uprobe/
+	// uretprobe programs attach to.
+	Executable string
+}
+
+// Links aggregates every link.Link returned by AttachAll. Closing it
+// detaches all of them; a zero value is a no-op.
+type Links []link.Link
+
+// Close detaches every link in ls, returning the first error encountered
+// after attempting to close them all.
+func (ls Links) Close() error {
+	var first error
+	for _, l := range ls {
+		if err := l.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// AttachAll creates the kernel link appropriate to each program in c,
+// inferring ProgramType/AttachType/AttachTo from the program's ELF section
+// name the same way LoadCollectionSpec did at load time. It lets callers
+// spin up an entire ELF with one call instead of iterating c.Programs and
+// dispatching per-type attach code themselves.
+//
+// Programs whose section name doesn't map to an attachable type (for
+// example SocketFilter, which is typically attached with setsockopt by
+// the caller) are skipped rather than treated as an error. If every
+// program is skipped, AttachAll returns an error.
+func (c *Collection) AttachAll(opts AttachOptions) (Links, error) {
+	var links Links
+
+	for name, prog := range c.Programs {
+		section := prog.SectionName()
+		pt, at, to, ok := LookupSection(section)
+		if !ok {
+			continue
+		}
+
+		l, err := attachOne(prog, pt, at, to, section, opts)
+		if err != nil {
+			links.Close()
+			return nil, fmt.Errorf("attach %s (%s): %w", name, section, err)
+		}
+		if l == nil {
+			continue
+		}
+
+		links = append(links, l)
+	}
+
+	if len(links) == 0 {
+		return nil, fmt.Errorf("ebpf: no attachable programs found")
+	}
+
+	return links, nil
+}
+
+func attachOne(prog *Program, pt ProgramType, at AttachType, to, section string, opts AttachOptions) (link.Link, error) {
+	switch pt {
+	case XDP:
+		return link.AttachXDP(link.XDPOptions{
+			Program:   prog,
+			Interface: opts.Interface,
+		})
+
+	case Kprobe:
+		switch {
+		case strings.HasPrefix(section, "uprobe/"), strings.HasPrefix(section, "uretprobe/"):
+			if opts.Executable == "" {
+				return nil, fmt.Errorf("%s requires AttachOptions.Executable", section)
+			}
+
+			ex, err := link.OpenExecutable(opts.Executable)
+			if err != nil {
+				return nil, fmt.Errorf("open %s: %w", opts.Executable, err)
+			}
+
+			if strings.HasPrefix(section, "uretprobe/") {
+				return ex.Uretprobe(to, prog, nil)
+			}
+			return ex.Uprobe(to, prog, nil)
+
+		case strings.HasPrefix(section, "kretprobe/"):
+			return link.Kretprobe(to, prog, nil)
+
+		default:
+			return link.Kprobe(to, prog, nil)
+		}
+
+	case RawTracepoint:
+		return link.AttachRawTracepoint(link.RawTracepointOptions{
+			Name:    to,
+			Program: prog,
+		})
+
+	case Tracing:
+		if at == AttachTraceIter {
+			return link.AttachIter(link.IterOptions{Program: prog})
+		}
+
+		return link.AttachTracing(link.TracingOptions{
+			Program:    prog,
+			AttachType: at,
+		})
+
+	case LSM:
+		return link.AttachLSM(link.LSMOptions{Program: prog})
+
+	case CGroupSKB, CGroupSock, CGroupSockAddr, CGroupDevice, CGroupSysctl:
+		if opts.Cgroup == nil {
+			return nil, fmt.Errorf("%s requires AttachOptions.Cgroup", section)
+		}
+		return link.AttachCgroup(link.CgroupOptions{
+			Path:    opts.Cgroup.Name(),
+			Attach:  at,
+			Program: prog,
+		})
+
+	default:
+		// No generic kernel link exists for this type (e.g. SocketFilter,
+		// SchedCLS, StructOps); callers attach those themselves.
+		return nil, nil
+	}
+}
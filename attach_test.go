@@ -0,0 +1,32 @@
+package ebpf
+
+import "testing"
+
+func TestAttachOneRequiresExecutableForUprobe(t *testing.T) {
+	_, err := attachOne(&Program{}, Kprobe, AttachNone, "func", "uprobe/func", AttachOptions{})
+	if err == nil {
+		t.Error("expected an error when AttachOptions.Executable is empty")
+	}
+
+	_, err = attachOne(&Program{}, Kprobe, AttachNone, "func", "uretprobe/func", AttachOptions{})
+	if err == nil {
+		t.Error("expected an error when AttachOptions.Executable is empty")
+	}
+}
+
+func TestAttachOneRequiresCgroupForCgroupPrograms(t *testing.T) {
+	_, err := attachOne(&Program{}, CGroupSKB, AttachCGroupInetIngress, "", "cgroup_skb/ingress", AttachOptions{})
+	if err == nil {
+		t.Error("expected an error when AttachOptions.Cgroup is nil")
+	}
+}
+
+func TestAttachOneSkipsUnattachableTypes(t *testing.T) {
+	l, err := attachOne(&Program{}, SocketFilter, AttachNone, "", "socket/filter", AttachOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l != nil {
+		t.Error("expected a nil link for a type with no generic kernel link")
+	}
+}
@@ -0,0 +1,103 @@
+// Command bpf2go generates Go bindings for a compiled eBPF object file.
+//
+// Given a pair of endian-suffixed ELFs (foo.bpfel.o and foo.bpfeb.o), it
+// embeds both and writes a <base>_bpfel_bpfeb.go file (named after the
+// object's base name unless -output is given) exposing a typed
+// struct per Map and Program, derived via CollectionSpec.Generate. This
+// lets downstream code reference coll.Maps["foo"] as obj.Foo instead,
+// with the compiler catching typos and type mismatches.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+func main() {
+	pkg := flag.String("pkg", "", "Go package name for the generated file (defaults to the directory name)")
+	output := flag.String("output", "", "output file (defaults to <elf base name>_bpfel_bpfeb.go)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: bpf2go [flags] <object.bpfel.o>")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *pkg, *output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(elfPath, pkg, output string) error {
+	base, endian, err := splitEndianSuffix(elfPath)
+	if err != nil {
+		return err
+	}
+
+	siblingSuffix := ".bpfeb.o"
+	if endian == "bpfeb" {
+		siblingSuffix = ".bpfel.o"
+	}
+
+	sibling := base + siblingSuffix
+	if _, err := os.Stat(sibling); err != nil {
+		return fmt.Errorf("%s: missing sibling object %s: %w", elfPath, sibling, err)
+	}
+
+	if pkg == "" {
+		dir, err := filepath.Abs(filepath.Dir(elfPath))
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(dir)
+	}
+
+	if output == "" {
+		output = base + "_bpfel_bpfeb.go"
+	}
+
+	// The generated file go:embeds "<objBase>.bpfel.o"/".bpfeb.o" relative
+	// to its own directory, so objBase must be base's path relative to
+	// output's directory, not base itself.
+	objBase, err := filepath.Rel(filepath.Dir(output), base)
+	if err != nil {
+		return err
+	}
+
+	spec, err := ebpf.LoadCollectionSpec(elfPath)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", elfPath, err)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := spec.Generate(pkg, objBase, f); err != nil {
+		return fmt.Errorf("generate %s: %w", output, err)
+	}
+
+	return nil
+}
+
+// splitEndianSuffix strips a trailing .bpfel.o or .bpfeb.o from path and
+// reports which endianness it named ("bpfel" or "bpfeb"), so callers can
+// locate the sibling object for the other endianness.
+func splitEndianSuffix(path string) (base, endian string, err error) {
+	for _, suffix := range []string{".bpfel.o", ".bpfeb.o"} {
+		if strings.HasSuffix(path, suffix) {
+			return strings.TrimSuffix(path, suffix), strings.TrimSuffix(strings.TrimPrefix(suffix, "."), ".o"), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s: expected a .bpfel.o or .bpfeb.o object", path)
+}
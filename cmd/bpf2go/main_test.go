@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestSplitEndianSuffix(t *testing.T) {
+	base, endian, err := splitEndianSuffix("/tmp/foo.bpfel.o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base != "/tmp/foo" || endian != "bpfel" {
+		t.Errorf("got base %q endian %q", base, endian)
+	}
+
+	base, endian, err = splitEndianSuffix("/tmp/foo.bpfeb.o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base != "/tmp/foo" || endian != "bpfeb" {
+		t.Errorf("got base %q endian %q", base, endian)
+	}
+
+	if _, _, err := splitEndianSuffix("/tmp/foo.o"); err == nil {
+		t.Error("expected an error for a non-endian-suffixed path")
+	}
+}
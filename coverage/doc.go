@@ -0,0 +1,11 @@
+// Package coverage instruments eBPF programs with a per-basic-block
+// counter so that tests exercising them (via Program.Test or Program.Run)
+// can report how much of the program actually ran.
+//
+// Instrument rewrites a CollectionSpec's program instructions before the
+// spec is loaded with NewCollection, inserting a map lookup and counter
+// increment at the start of every basic block. WriteProfile then turns
+// the counters back into a block-id -> (file, line, col) report, sourced
+// from the ELF's .BTF.ext line info, in the plain textfmt used by
+// `go tool covdata`.
+package coverage
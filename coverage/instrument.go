@@ -0,0 +1,272 @@
+package coverage
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+// counterMapName is the Array map Instrument adds to the spec; one u64
+// counter per basic block, indexed by Block.ID.
+const counterMapName = "coverage_counters"
+
+// Block identifies one basic block of an instrumented program and the
+// source location its leading instruction came from.
+type Block struct {
+	ID   int
+	File string
+	Line int
+	Col  int
+}
+
+// Coverage tracks the basic blocks Instrument inserted counters for,
+// across every program in a CollectionSpec.
+type Coverage struct {
+	spec   *ebpf.CollectionSpec
+	blocks map[string][]Block // program name -> blocks in id order
+}
+
+// Instrument rewrites every program in spec to increment a per-basic-block
+// counter on entry to the block, and adds the Array map backing those
+// counters to spec.Maps. It must be called before NewCollection /
+// NewCollectionWithOptions.
+//
+// The inserted counter sequence spills R0-R2 to stack offsets below
+// covKeySlot before using them and restores them afterwards, so it is
+// safe to insert even at block 0, where R1 still holds the program's
+// context argument. This does assume no instrumented program's own frame
+// reaches as deep as that reserved region.
+func Instrument(spec *ebpf.CollectionSpec) (*Coverage, error) {
+	cov := &Coverage{
+		spec:   spec,
+		blocks: make(map[string][]Block),
+	}
+
+	var total int
+	for name, prog := range spec.Programs {
+		blocks, err := instrumentProgram(prog, total)
+		if err != nil {
+			return nil, fmt.Errorf("coverage: instrument %s: %w", name, err)
+		}
+
+		cov.blocks[name] = blocks
+		total += len(blocks)
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("coverage: no instructions to instrument")
+	}
+
+	spec.Maps[counterMapName] = &ebpf.MapSpec{
+		Name:       counterMapName,
+		Type:       ebpf.Array,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: uint32(total),
+	}
+
+	return cov, nil
+}
+
+// prologueLen is the fixed number of instructions counterPrologue always
+// emits, regardless of block id. instrumentProgram needs it up front to
+// translate jump targets that survive instrumentation as raw offsets
+// rather than symbolic references.
+var prologueLen = len(counterPrologue(0))
+
+// instrumentProgram identifies the basic blocks of prog's instructions,
+// assigns each one an id starting at firstID, prepends a counter
+// increment to its leading instruction, and rewrites every raw-offset
+// jump so it lands on the start of the counter increment of the block it
+// targeted before instrumentation. A leader's symbol moves onto the first
+// instruction of its counter increment, so symbolic jumps and calls run
+// the counter too.
+//
+// Clang-compiled ELFs encode intra-function conditional/unconditional
+// jumps as a relative Offset with no Reference/Symbol -- relocations
+// only produce those for cross-function calls. Splicing instructions in
+// front of a jump's target (or in front of the jump itself) shifts that
+// raw offset out from under it, so every such jump's Offset has to be
+// recomputed against the new instruction positions.
+func instrumentProgram(prog *ebpf.ProgramSpec, firstID int) ([]Block, error) {
+	insns := prog.Instructions
+	leaders := basicBlockLeaders(insns)
+
+	// origToNew[i] is the index original instruction i occupies once
+	// every earlier leader's prologue has been spliced in; entryOf[i] is
+	// where a jump to i has to land, which is the start of i's own prologue
+	// when i is a leader. Both have an entry at len(insns) for jumps that
+	// target the implicit fallthrough past the last instruction.
+	origToNew := make([]int, len(insns)+1)
+	entryOf := make([]int, len(insns)+1)
+	pos := 0
+	for i := 0; i < len(insns); i++ {
+		entryOf[i] = pos
+		if leaders[i] {
+			pos += prologueLen
+		}
+		origToNew[i] = pos
+		pos++
+	}
+	origToNew[len(insns)] = pos
+	entryOf[len(insns)] = pos
+
+	var (
+		blocks    []Block
+		rewritten asm.Instructions
+	)
+
+	for i, ins := range insns {
+		if leaders[i] {
+			id := firstID + len(blocks)
+			file, line, col := lineInfo(prog, i)
+			blocks = append(blocks, Block{ID: id, File: file, Line: line, Col: col})
+			prologue := counterPrologue(id)
+			if sym := ins.Symbol(); sym != "" {
+				prologue[0] = prologue[0].WithSymbol(sym)
+				ins = ins.WithSymbol("")
+			}
+			rewritten = append(rewritten, prologue...)
+		}
+
+		if isRawOffsetJump(ins) {
+			target := i + 1 + int(ins.Offset)
+			if target < 0 || target > len(insns) {
+				return nil, fmt.Errorf("instruction %d: jump target %d out of range", i, target)
+			}
+
+			ins.Offset = int16(entryOf[target] - (origToNew[i] + 1))
+		}
+
+		rewritten = append(rewritten, ins)
+	}
+
+	prog.Instructions = rewritten
+
+	return blocks, nil
+}
+
+// isRawOffsetJump reports whether ins is a jump whose target is encoded
+// purely as a relative Offset rather than a symbolic Reference, and whose
+// Offset is therefore a real instruction-count distance that needs
+// adjusting when instructions are inserted around it. Call and Exit are
+// jump-class opcodes too, but neither carries a position-relative target.
+func isRawOffsetJump(ins asm.Instruction) bool {
+	if !ins.OpCode.Class().IsJump() {
+		return false
+	}
+
+	if op := ins.OpCode.JumpOp(); op == asm.Call || op == asm.Exit {
+		return false
+	}
+
+	return ins.Reference() == ""
+}
+
+// basicBlockLeaders returns, for every instruction index in insns, whether
+// that instruction starts a new basic block: the first instruction, a jump
+// target (whether encoded as a symbolic Reference or a raw Offset), or the
+// instruction immediately following a jump, call or exit.
+func basicBlockLeaders(insns asm.Instructions) map[int]bool {
+	leaders := map[int]bool{0: true}
+	symbolIndex := make(map[string]int, len(insns))
+
+	for i, ins := range insns {
+		if sym := ins.Symbol(); sym != "" {
+			symbolIndex[sym] = i
+		}
+	}
+
+	markLeader := func(i int) {
+		if i >= 0 && i < len(insns) {
+			leaders[i] = true
+		}
+	}
+
+	for i, ins := range insns {
+		switch {
+		case ins.IsFunctionCall() && ins.OpCode.JumpOp() == asm.Call:
+			markLeader(i + 1)
+
+		case isRawOffsetJump(ins):
+			markLeader(i + 1 + int(ins.Offset))
+			markLeader(i + 1)
+
+		case ins.OpCode.Class().IsJump():
+			if ref := ins.Reference(); ref != "" {
+				if target, ok := symbolIndex[ref]; ok {
+					markLeader(target)
+				}
+			}
+			markLeader(i + 1)
+		}
+	}
+
+	return leaders
+}
+
+// Stack slots the instrumentation reserves for itself, well below where a
+// compiled program's own frame is expected to reach, so that spilling
+// R0-R2 here doesn't collide with the block's live stack slots. The
+// prologue uses these, rather than the caller's registers, precisely
+// because a basic block leader runs with whatever was live in R0-R2 at
+// that point in the compiled program - including, at block 0, the
+// verifier-typed entry argument the program itself hasn't read yet.
+const (
+	covKeySlot = -512
+	covSpillR2 = -520
+	covSpillR1 = -528
+	covSpillR0 = -536
+)
+
+// counterPrologue emits the save/lookup/increment/restore sequence
+// inserted ahead of every basic block leader. It spills R0-R2 to
+// instrumentation-private stack slots before using them, and restores
+// them afterwards, so the block's own instructions still see whatever
+// was live in those registers when the block started - most importantly
+// R1, which holds the program's context argument at the very first
+// instruction.
+func counterPrologue(id int) asm.Instructions {
+	skip := fmt.Sprintf("cov_skip_%d", id)
+
+	return asm.Instructions{
+		asm.StoreMem(asm.RFP, covSpillR0, asm.R0, asm.DWord),
+		asm.StoreMem(asm.RFP, covSpillR1, asm.R1, asm.DWord),
+		asm.StoreMem(asm.RFP, covSpillR2, asm.R2, asm.DWord),
+
+		asm.Mov.Imm(asm.R1, int32(id)),
+		asm.StoreMem(asm.RFP, covKeySlot, asm.R1, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, covKeySlot),
+		asm.LoadMapPtr(asm.R1, 0).WithReference(counterMapName),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, skip),
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.DWord),
+		asm.Add.Imm(asm.R1, 1),
+		asm.StoreMem(asm.R0, 0, asm.R1, asm.DWord),
+
+		asm.LoadMem(asm.R0, asm.RFP, covSpillR0, asm.DWord).WithSymbol(skip),
+		asm.LoadMem(asm.R1, asm.RFP, covSpillR1, asm.DWord),
+		asm.LoadMem(asm.R2, asm.RFP, covSpillR2, asm.DWord),
+	}
+}
+
+// lineInfo looks up the source location of instruction i in prog's BTF
+// line info (.BTF.ext), falling back to the zero value when the program
+// was loaded without BTF.
+func lineInfo(prog *ebpf.ProgramSpec, i int) (file string, line, col int) {
+	bp, _ := prog.BTF.(interface {
+		Line(int) (string, int, int, bool)
+	})
+	if bp == nil {
+		return "", 0, 0
+	}
+
+	file, line, col, ok := bp.Line(i)
+	if !ok {
+		return "", 0, 0
+	}
+
+	return file, line, col
+}
@@ -0,0 +1,123 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+func TestBasicBlockLeaders(t *testing.T) {
+	insns := asm.Instructions{
+		asm.Mov.Imm(asm.R0, 1),
+		asm.JEq.Imm(asm.R0, 1, "target"),
+		asm.Mov.Imm(asm.R0, 2),
+		asm.Mov.Imm(asm.R0, 3).WithSymbol("target"),
+	}
+
+	leaders := basicBlockLeaders(insns)
+
+	want := map[int]bool{0: true, 2: true, 3: true}
+	for i := range insns {
+		if leaders[i] != want[i] {
+			t.Errorf("instruction %d: leader = %v, want %v", i, leaders[i], want[i])
+		}
+	}
+}
+
+func TestBasicBlockLeadersRawOffset(t *testing.T) {
+	// Clang-compiled jumps carry no Reference/Symbol: the target is purely
+	// the instruction-relative Offset. This jump at index 1 targets index
+	// 3 (Offset 1: one instruction past the next one).
+	jump := asm.JEq.Imm(asm.R0, 1, "")
+	jump.Offset = 1
+
+	insns := asm.Instructions{
+		asm.Mov.Imm(asm.R0, 1),
+		jump,
+		asm.Mov.Imm(asm.R0, 2),
+		asm.Mov.Imm(asm.R0, 3),
+	}
+
+	leaders := basicBlockLeaders(insns)
+
+	want := map[int]bool{0: true, 2: true, 3: true}
+	for i := range insns {
+		if leaders[i] != want[i] {
+			t.Errorf("instruction %d: leader = %v, want %v", i, leaders[i], want[i])
+		}
+	}
+}
+
+func TestInstrumentProgramAdjustsRawOffset(t *testing.T) {
+	jump := asm.JEq.Imm(asm.R0, 1, "")
+	jump.Offset = 1 // targets instruction 3: one past the next instruction
+
+	prog := &ebpf.ProgramSpec{
+		Instructions: asm.Instructions{
+			asm.Mov.Imm(asm.R0, 0), // 0: leader
+			jump,                   // 1: jumps to 3
+			asm.Mov.Imm(asm.R1, 1), // 2: leader (fallthrough after jump)
+			asm.Mov.Imm(asm.R1, 2), // 3: leader (jump target)
+		},
+	}
+
+	if _, err := instrumentProgram(prog, 0); err != nil {
+		t.Fatalf("instrumentProgram: %v", err)
+	}
+
+	// Every original instruction is now preceded by a prologue wherever it
+	// was a leader (0, 2, 3); the jump itself sits right after block 0's
+	// prologue and instruction 0, at index prologueLen+1.
+	jumpPos := prologueLen + 1
+	rewrittenJump := prog.Instructions[jumpPos]
+	if rewrittenJump.OpCode.JumpOp() != asm.JEq {
+		t.Fatalf("expected the rewritten jump at %d, got %+v", jumpPos, rewrittenJump)
+	}
+
+	// Block 2's prologue starts right after the jump; block 3's prologue
+	// follows one plain instruction later, and the jump must land on its
+	// first instruction so that entering block 3 by the jump is counted.
+	wantTarget := jumpPos + 1 + prologueLen + 1
+	gotTarget := jumpPos + 1 + int(rewrittenJump.Offset)
+	if gotTarget != wantTarget {
+		t.Errorf("rewritten jump lands on instruction %d, want %d", gotTarget, wantTarget)
+	}
+
+	if first := prog.Instructions[gotTarget]; first.Dst != asm.RFP || first.Src != asm.R0 {
+		t.Errorf("rewritten jump landed on %+v, want the start of block 3's prologue", first)
+	}
+
+	// And the prologue must be followed by the original "Mov R1, 2".
+	landed := prog.Instructions[gotTarget+prologueLen]
+	if landed.OpCode.ALUOp() != asm.Mov || landed.Constant != 2 {
+		t.Errorf("block 3's prologue is followed by %+v, want the original target instruction", landed)
+	}
+}
+
+func TestCounterPrologueRestoresRegisters(t *testing.T) {
+	prologue := counterPrologue(7)
+
+	// The first three and last three instructions must be the spill and
+	// restore of R0-R2, in that order, so a block relying on a live R1
+	// (e.g. the ctx argument at block 0) still sees it afterwards.
+	if len(prologue) < 6 {
+		t.Fatalf("counterPrologue returned too few instructions: %d", len(prologue))
+	}
+
+	spillRegs := []asm.Register{asm.R0, asm.R1, asm.R2}
+	for i, reg := range spillRegs {
+		if prologue[i].Dst != asm.RFP || prologue[i].Src != reg {
+			t.Errorf("spill instruction %d: want store of %v, got %+v", i, reg, prologue[i])
+		}
+	}
+
+	restoreRegs := []asm.Register{asm.R0, asm.R1, asm.R2}
+	restoreStart := len(prologue) - len(restoreRegs)
+	for i, reg := range restoreRegs {
+		ins := prologue[restoreStart+i]
+		if ins.Dst != reg || ins.Src != asm.RFP {
+			t.Errorf("restore instruction %d: want load into %v, got %+v", i, reg, ins)
+		}
+	}
+}
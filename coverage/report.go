@@ -0,0 +1,75 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cilium/ebpf"
+)
+
+// Snapshot reads the current counter values out of the kernel map backing
+// cov and returns them keyed by program name, in block-id order.
+func (cov *Coverage) Snapshot(coll *ebpf.Collection) (map[string][]uint64, error) {
+	m, ok := coll.Maps[counterMapName]
+	if !ok {
+		return nil, fmt.Errorf("coverage: collection has no %s map", counterMapName)
+	}
+
+	counts := make(map[string][]uint64, len(cov.blocks))
+	for name, blocks := range cov.blocks {
+		values := make([]uint64, len(blocks))
+		for i, b := range blocks {
+			var count uint64
+			if err := m.Lookup(uint32(b.ID), &count); err != nil {
+				return nil, fmt.Errorf("coverage: lookup block %d of %s: %w", b.ID, name, err)
+			}
+			values[i] = count
+		}
+		counts[name] = values
+	}
+
+	return counts, nil
+}
+
+// WriteProfile snapshots coll's counters and writes a `go tool covdata`
+// compatible textfmt profile to w: one line per block, "file:line.col,line.col 1 count",
+// where 1 is the block's statement count.
+//
+// Blocks without BTF line info (File == "") are omitted, since textfmt
+// has no representation for an unattributed block.
+func (cov *Coverage) WriteProfile(coll *ebpf.Collection, w io.Writer) error {
+	counts, err := cov.Snapshot(coll)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "mode: count"); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(cov.blocks))
+	for name := range cov.blocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		blocks := cov.blocks[name]
+		values := counts[name]
+
+		for i, b := range blocks {
+			if b.File == "" {
+				continue
+			}
+
+			_, err := fmt.Fprintf(w, "%s:%d.%d,%d.%d 1 %d\n",
+				b.File, b.Line, b.Col, b.Line, b.Col, values[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package ebpf
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/cilium/ebpf/internal"
+	"github.com/cilium/ebpf/internal/btf"
+)
+
+// Generate writes Go source to w that declares a strongly-typed wrapper
+// around cs: one field per MapSpec, one field per ProgramSpec, a Load<Pkg>
+// function that parses the embedded ELF into a CollectionSpec, and a
+// Load<Pkg>Objects constructor that loads that spec into the typed struct
+// via LoadAndAssign.
+//
+// pkg is used both as the package clause and, title-cased, as the prefix
+// for the generated loader function and types. objBase names the pair of
+// ELFs the generated file embeds via go:embed: objBase+".bpfel.o" and
+// objBase+".bpfeb.o", resolved relative to the directory the generated
+// file will live in. .bss, .data and .rodata maps are omitted from the
+// public API; their members are instead exposed through a
+// Rewrite<Pkg>Constants helper that calls RewriteConstants under the
+// hood, matching the `const volatile` globals recorded in the collection's
+// BTF.
+func (cs *CollectionSpec) Generate(pkg, objBase string, w io.Writer) error {
+	if pkg == "" {
+		return fmt.Errorf("generate: package name is required")
+	}
+	if objBase == "" {
+		return fmt.Errorf("generate: object file base name is required")
+	}
+
+	data := &genData{
+		Package: pkg,
+		Ident:   internal.Identifier(pkg),
+		ObjBase: objBase,
+	}
+
+	for name, ms := range cs.Maps {
+		switch name {
+		case ".bss", ".data", ".rodata":
+			consts, err := constantsFromSpec(ms)
+			if err != nil {
+				return fmt.Errorf("generate: constants for %s: %w", name, err)
+			}
+			data.Constants = append(data.Constants, consts...)
+			continue
+		}
+
+		m, err := keyValueTypes(pkg, name, ms)
+		if err != nil {
+			return fmt.Errorf("generate: map %s: %w", name, err)
+		}
+
+		m.Field = internal.Identifier(name)
+		m.Name = name
+		data.Maps = append(data.Maps, m)
+	}
+
+	for name := range cs.Programs {
+		data.Programs = append(data.Programs, genProgram{
+			Field: internal.Identifier(name),
+			Name:  name,
+		})
+	}
+
+	sort.Slice(data.Maps, func(i, j int) bool { return data.Maps[i].Name < data.Maps[j].Name })
+	sort.Slice(data.Programs, func(i, j int) bool { return data.Programs[i].Name < data.Programs[j].Name })
+	sort.Slice(data.Constants, func(i, j int) bool { return data.Constants[i].Name < data.Constants[j].Name })
+
+	var buf bytes.Buffer
+	if err := genTemplate.Execute(&buf, data); err != nil {
+		return fmt.Errorf("generate: execute template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generate: invalid generated source: %w", err)
+	}
+
+	_, err = w.Write(formatted)
+	return err
+}
+
+type genData struct {
+	Package   string
+	Ident     string
+	ObjBase   string
+	Maps      []genMap
+	Programs  []genProgram
+	Constants []genConstant
+}
+
+// genMap carries both the identifier and the full declaration for a map's
+// key/value types, since the no-BTF fallback and the BTF-derived path
+// produce declarations that look nothing alike.
+type genMap struct {
+	Field string
+	Name  string
+
+	KeyName   string
+	KeyDecl   string
+	ValueName string
+	ValueDecl string
+}
+
+type genProgram struct {
+	Field string
+	Name  string
+}
+
+type genConstant struct {
+	Name string
+	Type string
+}
+
+// keyValueTypes derives Go type declarations for a map's key and value
+// from the BTF attached to ms, falling back to a fixed-size byte array
+// declaration when no BTF is available (e.g. a map with no BTF.Map
+// pinned to it). The returned genMap's *Decl fields are complete `type
+// ... ` declarations, ready to drop straight into the generated file.
+func keyValueTypes(pkg, name string, ms *MapSpec) (genMap, error) {
+	keyName := internal.Identifier(pkg) + internal.Identifier(name) + "Key"
+	valueName := internal.Identifier(pkg) + internal.Identifier(name) + "Value"
+
+	bm, _ := ms.BTF.(*btf.Map)
+	if bm == nil {
+		return genMap{
+			KeyName:   keyName,
+			KeyDecl:   fmt.Sprintf("type %s [%d]byte", keyName, ms.KeySize),
+			ValueName: valueName,
+			ValueDecl: fmt.Sprintf("type %s [%d]byte", valueName, ms.ValueSize),
+		}, nil
+	}
+
+	keyDecl, err := btf.GoTypeDeclaration(keyName, bm.Key())
+	if err != nil {
+		return genMap{}, fmt.Errorf("key type: %w", err)
+	}
+
+	valueDecl, err := btf.GoTypeDeclaration(valueName, bm.Value())
+	if err != nil {
+		return genMap{}, fmt.Errorf("value type: %w", err)
+	}
+
+	return genMap{
+		KeyName:   keyName,
+		KeyDecl:   keyDecl,
+		ValueName: valueName,
+		ValueDecl: valueDecl,
+	}, nil
+}
+
+// constantsFromSpec walks the BTF of a .bss/.data/.rodata map and returns
+// one genConstant per `const volatile` global, so that Generate can emit
+// a setter that calls RewriteConstants under the hood.
+func constantsFromSpec(ms *MapSpec) ([]genConstant, error) {
+	bm, _ := ms.BTF.(*btf.Map)
+	if bm == nil {
+		return nil, nil
+	}
+
+	var consts []genConstant
+	for _, vsi := range bm.Value().(*btf.Datasec).Vars {
+		v, ok := vsi.Type.(*btf.Var)
+		if !ok || !isConstVolatile(v.Type) {
+			continue
+		}
+
+		goType, err := btf.GoTypeDeclaration("", v.Type)
+		if err != nil {
+			return nil, fmt.Errorf("constant %s: %w", v.Name, err)
+		}
+
+		consts = append(consts, genConstant{Name: v.Name, Type: goType})
+	}
+
+	return consts, nil
+}
+
+// isConstVolatile reports whether t is declared `const volatile`, i.e. its
+// qualifier chain contains both a Const and a Volatile wrapper. Const and
+// Volatile are type qualifiers wrapping the underlying BTF type, not a
+// storage-linkage property, so this has to walk t itself rather than
+// consult the variable's Linkage.
+func isConstVolatile(t btf.Type) bool {
+	var isConst, isVolatile bool
+
+	for {
+		switch v := t.(type) {
+		case *btf.Const:
+			isConst = true
+			t = v.Type
+		case *btf.Volatile:
+			isVolatile = true
+			t = v.Type
+		case *btf.Typedef:
+			t = v.Type
+		default:
+			return isConst && isVolatile
+		}
+	}
+}
+
+var genTemplate = template.Must(template.New("bpf2go").Funcs(template.FuncMap{
+	"title": strings.Title,
+}).Parse(genTemplateSrc))
+
+const genTemplateSrc = `// Code generated by bpf2go; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"bytes"
+	"fmt"
+
+	_ "embed"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/internal"
+)
+
+//go:embed {{ .ObjBase }}.bpfel.o
+var _{{ .Ident }}BpfelO []byte
+
+//go:embed {{ .ObjBase }}.bpfeb.o
+var _{{ .Ident }}BpfebO []byte
+
+{{ range .Maps }}
+{{ .KeyDecl }}
+
+{{ .ValueDecl }}
+{{ end }}
+// {{ title .Ident }}Objects contains all maps and programs embedded in
+// {{ .ObjBase }}.bpfel.o / {{ .ObjBase }}.bpfeb.o.
+//
+// It can be passed to Load{{ title .Ident }}Objects or used standalone after
+// a manual NewCollectionWithOptions call.
+type {{ title .Ident }}Objects struct {
+{{- range .Maps }}
+	{{ .Field }} *ebpf.Map ` + "`ebpf:\"{{ .Name }}\"`" + `
+{{- end }}
+{{- range .Programs }}
+	{{ .Field }} *ebpf.Program ` + "`ebpf:\"{{ .Name }}\"`" + `
+{{- end }}
+}
+
+// Close releases all maps and programs associated with obj.
+func (o *{{ title .Ident }}Objects) Close() error {
+{{- range .Maps }}
+	o.{{ .Field }}.Close()
+{{- end }}
+{{- range .Programs }}
+	o.{{ .Field }}.Close()
+{{- end }}
+	return nil
+}
+
+{{ range .Maps }}
+// {{ .Field }}Lookup looks up key in the {{ .Name }} map and decodes its
+// value into out.
+func (o *{{ title $.Ident }}Objects) {{ .Field }}Lookup(key {{ .KeyName }}, out *{{ .ValueName }}) error {
+	return o.{{ .Field }}.Lookup(&key, out)
+}
+
+// {{ .Field }}Put stores value under key in the {{ .Name }} map.
+func (o *{{ title $.Ident }}Objects) {{ .Field }}Put(key {{ .KeyName }}, value {{ .ValueName }}) error {
+	return o.{{ .Field }}.Put(&key, &value)
+}
+{{ end }}
+
+{{ if .Constants }}
+// Rewrite{{ title .Ident }}Constants rewrites the const volatile globals
+// declared in {{ .Package }}'s .rodata/.data/.bss sections before the
+// collection is loaded.
+func Rewrite{{ title .Ident }}Constants(spec *ebpf.CollectionSpec, consts map[string]interface{}) error {
+	for name := range consts {
+		switch name {
+{{- range .Constants }}
+		case "{{ .Name }}":
+{{- end }}
+		default:
+			return fmt.Errorf("unknown constant %q", name)
+		}
+	}
+
+	for _, ms := range spec.Maps {
+		if err := ms.RewriteConstants(consts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+{{ end }}
+
+// Load{{ title .Ident }} parses the embedded {{ .ObjBase }} ELF, selecting
+// the variant matching the host's endianness.
+func Load{{ title .Ident }}() (*ebpf.CollectionSpec, error) {
+	obj := _{{ .Ident }}BpfelO
+	if internal.NativeEndian == internal.BigEndian {
+		obj = _{{ .Ident }}BpfebO
+	}
+	return ebpf.LoadCollectionSpecFromReader(bytes.NewReader(obj))
+}
+
+// Load{{ title .Ident }}Objects loads {{ .ObjBase }} and stores all maps
+// and programs in obj.
+func Load{{ title .Ident }}Objects(obj *{{ title .Ident }}Objects, opts *ebpf.CollectionOptions) error {
+	spec, err := Load{{ title .Ident }}()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+`
@@ -0,0 +1,64 @@
+package ebpf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectionSpecGenerateNoBTF(t *testing.T) {
+	cs := &CollectionSpec{
+		Maps: map[string]*MapSpec{
+			"hash_map": {
+				Name:      "hash_map",
+				Type:      Hash,
+				KeySize:   4,
+				ValueSize: 8,
+			},
+		},
+		Programs: map[string]*ProgramSpec{
+			"xdp_prog": {
+				Name: "xdp_prog",
+				Type: XDP,
+			},
+		},
+	}
+
+	var buf strings.Builder
+	if err := cs.Generate("test", "test", &buf); err != nil {
+		t.Fatal("Generate:", err)
+	}
+
+	out := buf.String()
+
+	if strings.Contains(out, "type type ") {
+		t.Error("generated source has a doubled type keyword:\n", out)
+	}
+
+	if !strings.Contains(out, "type TestHashMapKey [4]byte") {
+		t.Error("generated source is missing the fallback key type:\n", out)
+	}
+
+	if !strings.Contains(out, "type TestHashMapValue [8]byte") {
+		t.Error("generated source is missing the fallback value type:\n", out)
+	}
+
+	if !strings.Contains(out, `//go:embed test.bpfel.o`) {
+		t.Error("generated source doesn't embed the object by its input base name:\n", out)
+	}
+
+	if !strings.Contains(out, "func (o *TestObjects) HashMapLookup(key TestHashMapKey, out *TestHashMapValue) error") {
+		t.Error("generated source is missing a typed accessor for hash_map:\n", out)
+	}
+}
+
+func TestCollectionSpecGenerateRequiresPkgAndObjBase(t *testing.T) {
+	cs := &CollectionSpec{}
+
+	if err := cs.Generate("", "test", new(strings.Builder)); err == nil {
+		t.Error("Generate with empty package name should fail")
+	}
+
+	if err := cs.Generate("test", "", new(strings.Builder)); err == nil {
+		t.Error("Generate with empty object base name should fail")
+	}
+}
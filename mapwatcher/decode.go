@@ -0,0 +1,41 @@
+package mapwatcher
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/internal/btf"
+)
+
+// defaultDecoders picks the key/value Decoders Watch uses for m when the
+// caller hasn't registered one via WatchOptions.Decoders: a BTF-derived
+// decoder when m was loaded with BTF, so callers get readable values
+// without writing their own decoder for every map, or passthrough
+// otherwise.
+func defaultDecoders(m *ebpf.Map, override Decoder) (key, value Decoder) {
+	if override != nil {
+		return override, override
+	}
+
+	bm := m.BTF()
+	if bm == nil {
+		return passthrough, passthrough
+	}
+
+	return btfDecoder(bm.Key()), btfDecoder(bm.Value())
+}
+
+// btfDecoder formats raw using bt, falling back to passthrough when bt is
+// nil (the map has no BTF for this side) or formatting fails (e.g. raw is
+// truncated).
+func btfDecoder(bt btf.Type) Decoder {
+	if bt == nil {
+		return passthrough
+	}
+
+	return func(raw []byte) []byte {
+		s, err := btf.FormatValue(bt, raw)
+		if err != nil {
+			return raw
+		}
+		return []byte(s)
+	}
+}
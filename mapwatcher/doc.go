@@ -0,0 +1,10 @@
+// Package mapwatcher streams updates out of the maps of a loaded
+// Collection without requiring callers to special-case each map type.
+//
+// Given a *ebpf.Collection, Watch inspects every MapSpec.Type and starts
+// whichever consumer fits: a ring buffer or perf event array reader for
+// RingBuf/PerfEventArray maps, and a polling scanner for Hash/Array/LRUHash
+// maps. Decoded key/value pairs are delivered to a single Receiver, so
+// downstream tools don't have to reimplement the ringbuf/perf plumbing for
+// every collection they watch.
+package mapwatcher
@@ -0,0 +1,246 @@
+package mapwatcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// DefaultPollInterval is used for Hash/Array/LRUHash maps when
+// WatchOptions.PollInterval is zero.
+const DefaultPollInterval = time.Second
+
+// Receiver is notified for every key/value pair observed in a watched map.
+// mapName is the name the map was declared under in the CollectionSpec.
+// key is nil for map types without an addressable key, such as RingBuf.
+type Receiver interface {
+	OnEvent(mapName string, key, value []byte)
+}
+
+// Decoder rewrites the raw bytes read from a map before they reach a
+// Receiver. Watch picks a default decoder from the map's BTF when one
+// isn't registered for its name via WatchOptions.Decoders; register one
+// explicitly to override that, or when the map was loaded without BTF.
+type Decoder func(raw []byte) []byte
+
+// WatchOptions configures which maps Watch attaches to and how often
+// poll-based maps are scanned.
+type WatchOptions struct {
+	// IncludeMaps restricts watching to these map names. Empty means all
+	// maps in the collection are considered, subject to ExcludeMaps.
+	IncludeMaps []string
+
+	// ExcludeMaps skips these map names even if they would otherwise be
+	// watched.
+	ExcludeMaps []string
+
+	// PollInterval is how often Hash/Array/LRUHash maps are rescanned.
+	// Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+
+	// Decoders overrides the default raw-bytes decoding for a given map
+	// name, keyed the same way as IncludeMaps/ExcludeMaps.
+	Decoders map[string]Decoder
+}
+
+// Watcher is the lifecycle handle returned by Watch. Cancelling the
+// context passed to Watch, or calling Close, stops every consumer; Wait
+// then returns once they have all exited.
+type Watcher struct {
+	group  *errgroup.Group
+	closer func() error
+}
+
+// Close stops all consumers started by Watch.
+func (w *Watcher) Close() error {
+	return w.closer()
+}
+
+// Wait blocks until every consumer has exited, returning the first error
+// any of them encountered.
+func (w *Watcher) Wait() error {
+	return w.group.Wait()
+}
+
+// Watch inspects coll's maps and starts an appropriate consumer for each
+// one selected by opts, forwarding decoded events to recv until ctx is
+// cancelled or the returned Watcher is closed.
+func Watch(ctx context.Context, coll *ebpf.Collection, recv Receiver, opts WatchOptions) (*Watcher, error) {
+	if recv == nil {
+		return nil, fmt.Errorf("mapwatcher: Receiver is required")
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	group, ctx := errgroup.WithContext(ctx)
+
+	started := 0
+	for name, m := range coll.Maps {
+		if !shouldWatch(name, opts) {
+			continue
+		}
+
+		decodeKey, decodeValue := defaultDecoders(m, opts.Decoders[name])
+
+		switch m.Type() {
+		case ebpf.RingBuf:
+			if err := watchRingBuf(group, ctx, name, m, recv, decodeValue); err != nil {
+				cancel()
+				return nil, fmt.Errorf("mapwatcher: ring buffer %s: %w", name, err)
+			}
+		case ebpf.PerfEventArray:
+			if err := watchPerfEvent(group, ctx, name, m, recv, decodeValue); err != nil {
+				cancel()
+				return nil, fmt.Errorf("mapwatcher: perf event array %s: %w", name, err)
+			}
+		case ebpf.Hash, ebpf.Array, ebpf.LRUHash:
+			watchPoll(group, ctx, name, m, recv, decodeKey, decodeValue, interval)
+		default:
+			continue
+		}
+
+		started++
+	}
+
+	if started == 0 {
+		cancel()
+		return nil, fmt.Errorf("mapwatcher: no watchable maps matched %+v", opts)
+	}
+
+	return &Watcher{group: group, closer: func() error { cancel(); return nil }}, nil
+}
+
+func shouldWatch(name string, opts WatchOptions) bool {
+	if len(opts.IncludeMaps) > 0 && !contains(opts.IncludeMaps, name) {
+		return false
+	}
+	return !contains(opts.ExcludeMaps, name)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func passthrough(raw []byte) []byte { return raw }
+
+func watchRingBuf(group *errgroup.Group, ctx context.Context, name string, m *ebpf.Map, recv Receiver, decode Decoder) error {
+	rd, err := ringbuf.NewReader(m)
+	if err != nil {
+		return err
+	}
+
+	group.Go(func() error {
+		defer rd.Close()
+		go func() {
+			<-ctx.Done()
+			rd.Close()
+		}()
+
+		for {
+			record, err := rd.Read()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("read: %w", err)
+			}
+
+			recv.OnEvent(name, nil, decode(record.RawSample))
+		}
+	})
+
+	return nil
+}
+
+func watchPerfEvent(group *errgroup.Group, ctx context.Context, name string, m *ebpf.Map, recv Receiver, decode Decoder) error {
+	rd, err := perf.NewReader(m, os.Getpagesize())
+	if err != nil {
+		return err
+	}
+
+	group.Go(func() error {
+		defer rd.Close()
+		go func() {
+			<-ctx.Done()
+			rd.Close()
+		}()
+
+		for {
+			record, err := rd.Read()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("read: %w", err)
+			}
+
+			if record.LostSamples > 0 {
+				continue
+			}
+
+			recv.OnEvent(name, nil, decode(record.RawSample))
+		}
+	})
+
+	return nil
+}
+
+func watchPoll(group *errgroup.Group, ctx context.Context, name string, m *ebpf.Map, recv Receiver, decodeKey, decodeValue Decoder, interval time.Duration) {
+	group.Go(func() error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := scan(name, m, recv, decodeKey, decodeValue); err != nil {
+					return fmt.Errorf("scan %s: %w", name, err)
+				}
+			}
+		}
+	})
+}
+
+// scan iterates every entry of m once, reporting entries.Err() instead of
+// silently dropping a failed iteration (e.g. the map was deleted
+// underneath us).
+// scan walks every entry of m once, decoding and delivering each one to
+// recv. entries.Next reuses the key/value slices it's handed across
+// calls, and passthrough decoding hands those same slices straight back,
+// so the raw bytes are copied before OnEvent ever sees them -- otherwise
+// the next iteration (or ringbuf/perf reuse further up the stack) would
+// mutate a value out from under a Receiver that doesn't consume it
+// synchronously, which is the entire point of this package's streaming
+// API.
+func scan(name string, m *ebpf.Map, recv Receiver, decodeKey, decodeValue Decoder) error {
+	var key, value []byte
+	entries := m.Iterate()
+	for entries.Next(&key, &value) {
+		recv.OnEvent(name, decodeKey(copyBytes(key)), decodeValue(copyBytes(value)))
+	}
+	return entries.Err()
+}
+
+func copyBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
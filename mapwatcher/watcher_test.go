@@ -0,0 +1,49 @@
+package mapwatcher
+
+import "testing"
+
+func TestContains(t *testing.T) {
+	list := []string{"foo", "bar"}
+
+	if !contains(list, "foo") {
+		t.Error("expected foo to be found")
+	}
+
+	if contains(list, "baz") {
+		t.Error("baz should not be found")
+	}
+}
+
+func TestShouldWatch(t *testing.T) {
+	cases := []struct {
+		name string
+		opts WatchOptions
+		want bool
+	}{
+		{"foo", WatchOptions{}, true},
+		{"foo", WatchOptions{IncludeMaps: []string{"foo"}}, true},
+		{"foo", WatchOptions{IncludeMaps: []string{"bar"}}, false},
+		{"foo", WatchOptions{ExcludeMaps: []string{"foo"}}, false},
+	}
+
+	for _, tc := range cases {
+		if got := shouldWatch(tc.name, tc.opts); got != tc.want {
+			t.Errorf("shouldWatch(%q, %+v) = %v, want %v", tc.name, tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	raw := []byte{1, 2, 3}
+	if got := passthrough(raw); string(got) != string(raw) {
+		t.Errorf("passthrough modified its input: %v", got)
+	}
+}
+
+func TestBtfDecoderNilType(t *testing.T) {
+	decode := btfDecoder(nil)
+	raw := []byte{1, 2, 3}
+	if got := decode(raw); string(got) != string(raw) {
+		t.Errorf("btfDecoder(nil) should fall back to passthrough, got %v", got)
+	}
+}
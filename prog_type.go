@@ -0,0 +1,121 @@
+package ebpf
+
+import "strings"
+
+// sectionHandler maps an ELF section name prefix to the ProgramType and
+// AttachType it implies, plus an optional extractor for the remainder of
+// the section name (e.g. the probed symbol in "kprobe/sys_open").
+type sectionHandler struct {
+	prefix          string
+	programType     ProgramType
+	attachType      AttachType
+	extractAttachTo func(section string) string
+}
+
+// sectionPrefixes is consulted longest-prefix-first, so a more specific
+// registration such as "cgroup_skb/ingress" takes priority over the
+// generic "cgroup_skb" fallback registered alongside it.
+var sectionPrefixes []sectionHandler
+
+// RegisterSectionPrefix adds a mapping from an ELF section name prefix to
+// a ProgramType/AttachType pair, for use by getProgType/LookupSection.
+//
+// extractAttachTo may be nil if the section carries no additional
+// attachment information beyond the prefix itself. Callers vendoring
+// libbpf-style ELFs with prefixes this package doesn't know about yet
+// (for example a newer fentry/fexit/lsm/struct_ops variant) can call this
+// from an init function to teach the loader about them without patching
+// the library.
+func RegisterSectionPrefix(prefix string, pt ProgramType, at AttachType, extractAttachTo func(section string) string) {
+	sectionPrefixes = append(sectionPrefixes, sectionHandler{prefix, pt, at, extractAttachTo})
+}
+
+func suffixAfter(prefix string) func(section string) string {
+	return func(section string) string {
+		return strings.TrimPrefix(section, prefix)
+	}
+}
+
+func init() {
+	RegisterSectionPrefix("socket", SocketFilter, AttachNone, nil)
+	RegisterSectionPrefix("seccomp", SocketFilter, AttachNone, nil)
+	RegisterSectionPrefix("kprobe/", Kprobe, AttachNone, suffixAfter("kprobe/"))
+	RegisterSectionPrefix("kretprobe/", Kprobe, AttachNone, suffixAfter("kretprobe/"))
+	RegisterSectionPrefix("uprobe/", Kprobe, AttachNone, suffixAfter("uprobe/"))
+	RegisterSectionPrefix("uretprobe/", Kprobe, AttachNone, suffixAfter("uretprobe/"))
+	RegisterSectionPrefix("tracepoint/", TracePoint, AttachNone, suffixAfter("tracepoint/"))
+	RegisterSectionPrefix("tp/", TracePoint, AttachNone, suffixAfter("tp/"))
+	RegisterSectionPrefix("raw_tracepoint/", RawTracepoint, AttachNone, suffixAfter("raw_tracepoint/"))
+	RegisterSectionPrefix("raw_tp/", RawTracepoint, AttachNone, suffixAfter("raw_tp/"))
+	RegisterSectionPrefix("tp_btf/", Tracing, AttachTraceRawTp, suffixAfter("tp_btf/"))
+	RegisterSectionPrefix("fentry/", Tracing, AttachTraceFEntry, suffixAfter("fentry/"))
+	RegisterSectionPrefix("fexit/", Tracing, AttachTraceFExit, suffixAfter("fexit/"))
+	RegisterSectionPrefix("lsm/", LSM, AttachLSMMac, suffixAfter("lsm/"))
+	RegisterSectionPrefix("iter/", Tracing, AttachTraceIter, suffixAfter("iter/"))
+	RegisterSectionPrefix("struct_ops/", StructOps, AttachNone, suffixAfter("struct_ops/"))
+	RegisterSectionPrefix("sk_lookup/", SkLookup, AttachSkLookup, nil)
+	RegisterSectionPrefix("xdp/", XDP, AttachNone, nil)
+	RegisterSectionPrefix("xdp", XDP, AttachNone, nil)
+	RegisterSectionPrefix("classifier", SchedCLS, AttachNone, nil)
+	RegisterSectionPrefix("action", SchedACT, AttachNone, nil)
+	RegisterSectionPrefix("sockops", SockOps, AttachCGroupSockOps, nil)
+	RegisterSectionPrefix("sk_skb", SkSKB, AttachNone, nil)
+	RegisterSectionPrefix("sk_msg", SkMsg, AttachSkMsgVerdict, nil)
+	RegisterSectionPrefix("sk_reuseport", SkReuseport, AttachNone, nil)
+	RegisterSectionPrefix("netfilter/", Netfilter, AttachNetfilter, nil)
+
+	RegisterSectionPrefix("cgroup_skb", CGroupSKB, AttachNone, nil)
+	RegisterSectionPrefix("cgroup_skb/ingress", CGroupSKB, AttachCGroupInetIngress, nil)
+	RegisterSectionPrefix("cgroup_skb/egress", CGroupSKB, AttachCGroupInetEgress, nil)
+	RegisterSectionPrefix("cgroup/skb", CGroupSKB, AttachNone, nil)
+	RegisterSectionPrefix("cgroup/sock", CGroupSock, AttachCGroupInetSockCreate, nil)
+	RegisterSectionPrefix("cgroup/connect4", CGroupSockAddr, AttachCGroupInet4Connect, nil)
+	RegisterSectionPrefix("cgroup/connect6", CGroupSockAddr, AttachCGroupInet6Connect, nil)
+	RegisterSectionPrefix("cgroup/post_bind4", CGroupSock, AttachCGroupInet4PostBind, nil)
+	RegisterSectionPrefix("cgroup/post_bind6", CGroupSock, AttachCGroupInet6PostBind, nil)
+	RegisterSectionPrefix("cgroup/dev", CGroupDevice, AttachCGroupDevice, nil)
+	RegisterSectionPrefix("cgroup/sysctl", CGroupSysctl, AttachCGroupSysctl, nil)
+}
+
+// LookupSection resolves an ELF section name to the ProgramType/AttachType
+// pair registered for its longest matching prefix, and whatever attachment
+// information (probed symbol, tracepoint name, ...) that prefix's
+// extractor derives from the rest of the section name.
+//
+// The boolean result reports whether any registered prefix matched.
+func LookupSection(section string) (ProgramType, AttachType, string, bool) {
+	var best *sectionHandler
+	for i := range sectionPrefixes {
+		h := &sectionPrefixes[i]
+		if !strings.HasPrefix(section, h.prefix) {
+			continue
+		}
+		if best == nil || len(h.prefix) > len(best.prefix) {
+			best = h
+		}
+	}
+
+	if best == nil {
+		return UnspecifiedProgram, AttachNone, "", false
+	}
+
+	var attachTo string
+	if best.extractAttachTo != nil {
+		attachTo = best.extractAttachTo(section)
+	}
+
+	return best.programType, best.attachType, attachTo, true
+}
+
+// getProgType infers a ProgramSpec's Type, AttachType and AttachTo from
+// its ELF section name, using the registry populated by
+// RegisterSectionPrefix. Unknown sections are returned as SocketFilter,
+// matching the historical default for sections with no recognised prefix.
+func getProgType(sectionName string) (ProgramType, AttachType, string) {
+	pt, at, to, ok := LookupSection(sectionName)
+	if !ok {
+		return SocketFilter, AttachNone, ""
+	}
+
+	return pt, at, to
+}
@@ -0,0 +1,61 @@
+package ebpf
+
+import "testing"
+
+func TestLookupSection(t *testing.T) {
+	testcases := []struct {
+		section string
+		pt      ProgramType
+		at      AttachType
+		to      string
+		ok      bool
+	}{
+		{"socket/garbage", SocketFilter, AttachNone, "", true},
+		{"kprobe/func", Kprobe, AttachNone, "func", true},
+		{"xdp/foo", XDP, AttachNone, "", true},
+		{"cgroup_skb/ingress", CGroupSKB, AttachCGroupInetIngress, "", true},
+		{"cgroup_skb/garbage", CGroupSKB, AttachNone, "", true},
+		{"iter/bpf_map", Tracing, AttachTraceIter, "bpf_map", true},
+		{"fentry/do_unlinkat", Tracing, AttachTraceFEntry, "do_unlinkat", true},
+		{"totally_unknown/section", UnspecifiedProgram, AttachNone, "", false},
+	}
+
+	for _, tc := range testcases {
+		pt, at, to, ok := LookupSection(tc.section)
+		if ok != tc.ok {
+			t.Errorf("section %s: expected ok %v, got %v", tc.section, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if pt != tc.pt {
+			t.Errorf("section %s: expected type %v, got %v", tc.section, tc.pt, pt)
+		}
+
+		if at != tc.at {
+			t.Errorf("section %s: expected attach type %v, got %v", tc.section, tc.at, at)
+		}
+
+		if to != tc.to {
+			t.Errorf("section %s: expected attachment to be %q, got %q", tc.section, tc.to, to)
+		}
+	}
+}
+
+func TestRegisterSectionPrefixLongestMatchWins(t *testing.T) {
+	const prefix = "totally_unknown/specific"
+
+	RegisterSectionPrefix("totally_unknown/", UnspecifiedProgram, AttachNone, nil)
+	RegisterSectionPrefix(prefix, XDP, AttachNone, nil)
+
+	pt, _, _, ok := LookupSection(prefix + "/thing")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+
+	if pt != XDP {
+		t.Errorf("expected the more specific prefix to win, got %v", pt)
+	}
+}